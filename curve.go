@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CurvePoint is one control point of a fan curve: at Temp degrees
+// Celsius the fan should run at RPM.
+type CurvePoint struct {
+	Temp float64 `yaml:"temp"`
+	RPM  int64   `yaml:"rpm"`
+}
+
+// interpolateCurve returns the fan speed for temp by linearly
+// interpolating between the two curve points surrounding it, clamping
+// to the first/last point outside the curve's range. points must be
+// sorted by Temp ascending.
+func interpolateCurve(points []CurvePoint, temp float64) float64 {
+	if len(points) == 0 {
+		return 0
+	}
+
+	if temp <= points[0].Temp {
+		return float64(points[0].RPM)
+	}
+
+	last := points[len(points)-1]
+
+	if temp >= last.Temp {
+		return float64(last.RPM)
+	}
+
+	for i := 1; i < len(points); i++ {
+		prev, next := points[i-1], points[i]
+
+		if temp > next.Temp {
+			continue
+		}
+
+		ratio := (temp - prev.Temp) / (next.Temp - prev.Temp)
+
+		return float64(prev.RPM) + ratio*float64(next.RPM-prev.RPM)
+	}
+
+	return float64(last.RPM)
+}
+
+// applyStep slews current toward target by at most upStep (when
+// rising) or downStep (when falling) RPM per tick. A zero step means
+// unlimited movement in that direction.
+func applyStep(current, target, upStep, downStep int64) int64 {
+	delta := target - current
+
+	if delta > 0 && upStep > 0 && delta > upStep {
+		return current + upStep
+	}
+
+	if delta < 0 && downStep > 0 && -delta > downStep {
+		return current - downStep
+	}
+
+	return target
+}
+
+// reduce collapses a set of sensor readings to a single temperature
+// according to mode: mean, max, median or percentile:N.
+func reduce(values sensorsValues, mode string) (float64, error) {
+	if len(values) == 0 {
+		return 0, fmt.Errorf("no temperature values to reduce")
+	}
+
+	sorted := make([]float64, 0, len(values))
+
+	for _, v := range values {
+		sorted = append(sorted, v)
+	}
+
+	sort.Float64s(sorted)
+
+	switch {
+	case mode == "mean":
+		var sum float64
+
+		for _, v := range sorted {
+			sum += v
+		}
+
+		return sum / float64(len(sorted)), nil
+	case mode == "max":
+		return sorted[len(sorted)-1], nil
+	case mode == "median":
+		return percentile(sorted, 50), nil
+	case strings.HasPrefix(mode, "percentile:"):
+		n, err := strconv.ParseFloat(strings.TrimPrefix(mode, "percentile:"), 64)
+
+		if err != nil {
+			return 0, fmt.Errorf("unrecognized mode '%s': %s", mode, err)
+		}
+
+		return percentile(sorted, n), nil
+	default:
+		return 0, fmt.Errorf("unrecognized mode '%s'. should be 'mean', 'max', 'median' or 'percentile:N'", mode)
+	}
+}
+
+// percentile returns the n-th percentile (0-100) of an already sorted
+// slice, linearly interpolating between the two nearest ranks.
+func percentile(sorted []float64, n float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+
+	rank := n / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	if lower == upper {
+		return sorted[lower]
+	}
+
+	weight := rank - float64(lower)
+
+	return sorted[lower]*(1-weight) + sorted[upper]*weight
+}
@@ -0,0 +1,171 @@
+package main
+
+import "testing"
+
+func TestInterpolateCurve(t *testing.T) {
+	curve := []CurvePoint{
+		{Temp: 40, RPM: 1500},
+		{Temp: 60, RPM: 3000},
+		{Temp: 80, RPM: 5000},
+	}
+
+	tests := []struct {
+		name string
+		temp float64
+		want float64
+	}{
+		{"below range clamps to first point", 10, 1500},
+		{"at first point", 40, 1500},
+		{"midway of first segment", 50, 2250},
+		{"at middle point", 60, 3000},
+		{"midway of second segment", 70, 4000},
+		{"at last point", 80, 5000},
+		{"above range clamps to last point", 100, 5000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := interpolateCurve(curve, tt.temp); got != tt.want {
+				t.Errorf("interpolateCurve(%v) = %v, want %v", tt.temp, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterpolateCurveEmpty(t *testing.T) {
+	if got := interpolateCurve(nil, 50); got != 0 {
+		t.Errorf("interpolateCurve(nil) = %v, want 0", got)
+	}
+}
+
+func TestApplyStep(t *testing.T) {
+	tests := []struct {
+		name             string
+		current, target  int64
+		upStep, downStep int64
+		want             int64
+	}{
+		{"no limits reaches target directly", 1000, 4000, 0, 0, 4000},
+		{"rise clamped by upStep", 1000, 4000, 500, 500, 1500},
+		{"fall clamped by downStep", 4000, 1000, 500, 500, 3500},
+		{"rise within upStep reaches target", 1000, 1200, 500, 500, 1200},
+		{"no change needed", 2000, 2000, 500, 500, 2000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applyStep(tt.current, tt.target, tt.upStep, tt.downStep); got != tt.want {
+				t.Errorf("applyStep(%d, %d, %d, %d) = %d, want %d", tt.current, tt.target, tt.upStep, tt.downStep, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReduce(t *testing.T) {
+	values := sensorsValues{
+		"a": 40,
+		"b": 50,
+		"c": 60,
+		"d": 70,
+	}
+
+	tests := []struct {
+		mode string
+		want float64
+	}{
+		{"mean", 55},
+		{"max", 70},
+		{"median", 55},
+		{"percentile:90", 67},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got, err := reduce(values, tt.mode)
+
+			if err != nil {
+				t.Fatalf("reduce(%s) returned error: %s", tt.mode, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("reduce(%s) = %v, want %v", tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReduceUnrecognizedMode(t *testing.T) {
+	if _, err := reduce(sensorsValues{"a": 1}, "bogus"); err == nil {
+		t.Error("expected error for unrecognized mode, got nil")
+	}
+}
+
+func TestZoneComputeNewFanSpeedHysteresis(t *testing.T) {
+	z := &Zone{
+		Mode:       "mean",
+		Hysteresis: 5,
+		Curve: []CurvePoint{
+			{Temp: 40, RPM: 1500},
+			{Temp: 80, RPM: 5000},
+		},
+	}
+
+	// First tick always applies, regardless of hysteresis.
+	_, rpm, changed, err := z.computeNewFanSpeed(sensorsValues{"a": 40})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !changed || rpm != 1500 {
+		t.Fatalf("first tick: got rpm=%d changed=%v, want rpm=1500 changed=true", rpm, changed)
+	}
+
+	// A small temperature move inside the hysteresis band keeps the
+	// previous speed.
+	_, rpm, changed, err = z.computeNewFanSpeed(sensorsValues{"a": 43})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if changed || rpm != 1500 {
+		t.Fatalf("inside hysteresis band: got rpm=%d changed=%v, want rpm=1500 changed=false", rpm, changed)
+	}
+
+	// A move past the hysteresis band recomputes the setpoint.
+	_, rpm, changed, err = z.computeNewFanSpeed(sensorsValues{"a": 60})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !changed || rpm != 3250 {
+		t.Fatalf("past hysteresis band: got rpm=%d changed=%v, want rpm=3250 changed=true", rpm, changed)
+	}
+}
+
+func TestZoneComputeNewFanSpeedStep(t *testing.T) {
+	z := &Zone{
+		Mode:   "mean",
+		UpStep: 200,
+		Curve: []CurvePoint{
+			{Temp: 40, RPM: 1500},
+			{Temp: 80, RPM: 5000},
+		},
+	}
+
+	if _, _, _, err := z.computeNewFanSpeed(sensorsValues{"a": 40}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, rpm, changed, err := z.computeNewFanSpeed(sensorsValues{"a": 80})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !changed || rpm != 1700 {
+		t.Fatalf("stepped climb: got rpm=%d changed=%v, want rpm=1700 changed=true", rpm, changed)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/anatol/smart.go"
+)
+
+// diskSource augments another SensorSource with SMART disk
+// temperatures, merged in under `disk:<device>` keys. Devices are
+// opened once at startup and closed alongside the wrapped source, so
+// callers still only need a single deferred teardown.
+type diskSource struct {
+	inner   SensorSource
+	devices map[string]smart.Device
+}
+
+func newDiskSource(inner SensorSource, disks []string) (*diskSource, error) {
+	names := disks
+
+	if containsString(names, "auto") {
+		discovered, err := discoverDisks()
+
+		if err != nil {
+			return nil, fmt.Errorf("discovering disks: %s", err)
+		}
+
+		names = discovered
+	}
+
+	devices := make(map[string]smart.Device, len(names))
+
+	for _, name := range names {
+		dev, err := smart.Open("/dev/" + name)
+
+		if err != nil {
+			log.WithField("disk", name).WithError(err).Warn("opening disk, skipping")
+			continue
+		}
+
+		devices[name] = dev
+	}
+
+	return &diskSource{inner: inner, devices: devices}, nil
+}
+
+func (s *diskSource) Read() (map[string]float64, error) {
+	values, err := s.inner.Read()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for name, dev := range s.devices {
+		temperature, err := diskTemperature(dev)
+
+		if err != nil {
+			log.WithField("disk", name).WithError(err).Error("reading disk temperature")
+			continue
+		}
+
+		values["disk:"+name] = temperature
+	}
+
+	return values, nil
+}
+
+func (s *diskSource) Close() {
+	s.inner.Close()
+
+	for _, dev := range s.devices {
+		dev.Close()
+	}
+}
+
+// Units reports "°C" for every disk key this source adds, plus
+// whatever the wrapped source reports for its own keys.
+func (s *diskSource) Units() map[string]string {
+	units := make(map[string]string)
+
+	if inner, ok := s.inner.(UnitSource); ok {
+		for key, unit := range inner.Units() {
+			units[key] = unit
+		}
+	}
+
+	for name := range s.devices {
+		units["disk:"+name] = "°C"
+	}
+
+	return units
+}
+
+// diskTemperature reads the current temperature via the generic
+// attributes API, which NVMe, SATA and SCSI devices all implement
+// alike.
+func diskTemperature(dev smart.Device) (float64, error) {
+	attrs, err := dev.ReadGenericAttributes()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(attrs.Temperature), nil
+}
+
+// discoverDisks lists real SATA/SCSI/NVMe block devices under
+// /sys/block, for the `auto` entry in the `disks` config. Entries
+// without a `device` symlink -- loop, zram, dm-*, ram, etc. -- are
+// virtual block devices smart.Open can never succeed against, so
+// they're excluded here rather than left for newDiskSource to warn
+// about one by one.
+func discoverDisks() ([]string, error) {
+	entries, err := ioutil.ReadDir("/sys/block")
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join("/sys/block", entry.Name(), "device")); err != nil {
+			continue
+		}
+
+		names = append(names, entry.Name())
+	}
+
+	return names, nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+
+	return false
+}
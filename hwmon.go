@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	hwmonTempRe = regexp.MustCompile(`^temp(\d+)_input$`)
+	hwmonFanRe  = regexp.MustCompile(`^fan(\d+)_input$`)
+)
+
+// hwmonSource reads /sys/class/hwmon directly, for minimal systems
+// without lm-sensors installed at all.
+type hwmonSource struct {
+	// units is rebuilt on every Read, keyed the same as its returned
+	// values, since hwmonLabel discards the raw "tempN"/"fanN" feature
+	// name Units needs once it finds a *_label file to use instead.
+	units map[string]string
+}
+
+func newHwmonSource() *hwmonSource {
+	return &hwmonSource{}
+}
+
+func (s *hwmonSource) Read() (map[string]float64, error) {
+	dirs, err := filepath.Glob("/sys/class/hwmon/*")
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]float64)
+	units := make(map[string]string)
+
+	for _, dir := range dirs {
+		chip, err := readHwmonString(filepath.Join(dir, "name"))
+
+		if err != nil {
+			continue
+		}
+
+		entries, err := ioutil.ReadDir(dir)
+
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			fileName := entry.Name()
+
+			if m := hwmonTempRe.FindStringSubmatch(fileName); m != nil {
+				milliC, err := readHwmonFloat(filepath.Join(dir, fileName))
+
+				if err != nil {
+					continue
+				}
+
+				feature := "temp" + m[1]
+				key := chip + ":" + hwmonLabel(dir, feature)
+				values[key] = milliC / 1000
+				units[key] = sensorUnit(feature)
+				continue
+			}
+
+			if m := hwmonFanRe.FindStringSubmatch(fileName); m != nil {
+				rpm, err := readHwmonFloat(filepath.Join(dir, fileName))
+
+				if err != nil {
+					continue
+				}
+
+				feature := "fan" + m[1]
+				key := chip + ":" + hwmonLabel(dir, feature)
+				values[key] = rpm
+				units[key] = sensorUnit(feature)
+			}
+		}
+	}
+
+	s.units = units
+
+	return values, nil
+}
+
+func (s *hwmonSource) Close() {}
+
+// Units reports the unit hints gathered by the most recent Read.
+func (s *hwmonSource) Units() map[string]string {
+	return s.units
+}
+
+// hwmonLabel prefers the feature's optional *_label file, falling back
+// to the raw feature name (e.g. "temp1") when there isn't one.
+func hwmonLabel(dir, feature string) string {
+	if label, err := readHwmonString(filepath.Join(dir, feature+"_label")); err == nil {
+		return label
+	}
+
+	return feature
+}
+
+func readHwmonString(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+func readHwmonFloat(path string) (float64, error) {
+	content, err := readHwmonString(path)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(content, 64)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/md14454/gosensors"
+)
+
+// libsensorsSource reads chip features through cgo bindings to
+// libsensors, the original and default backend.
+type libsensorsSource struct {
+	// units is rebuilt on every Read, keyed the same as its returned
+	// values, since the label used as the key discards the raw
+	// "tempN"/"fanN" feature name Units needs.
+	units map[string]string
+}
+
+func newLibsensorsSource() *libsensorsSource {
+	gosensors.Init()
+	return &libsensorsSource{}
+}
+
+func (s *libsensorsSource) Read() (map[string]float64, error) {
+	values := make(map[string]float64)
+	units := make(map[string]string)
+
+	for _, chip := range gosensors.GetDetectedChips() {
+		for _, feature := range chip.GetFeatures() {
+			sensorName := strings.TrimSpace(chip.String() + ":" + feature.GetLabel())
+			values[sensorName] = feature.GetValue()
+			units[sensorName] = sensorUnit(feature.Name())
+		}
+	}
+
+	s.units = units
+
+	return values, nil
+}
+
+func (s *libsensorsSource) Close() {
+	gosensors.Cleanup()
+}
+
+// Units reports the unit hints gathered by the most recent Read.
+func (s *libsensorsSource) Units() map[string]string {
+	return s.units
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// listConfig reads just the fields listSensors needs out of
+// `~/.autofan`, so `autofan list` reflects whatever backend the user
+// has actually configured instead of assuming libsensors.
+type listConfig struct {
+	Source string   `yaml:"source"`
+	Disks  []string `yaml:"disks"`
+}
+
+// listSensors builds the same SensorSource the daemon would use for
+// the configured `source` (and `disks`, if set), reads it once and
+// prints every `chip:label` it finds grouped by chip with its current
+// value and unit, when the backend can report one. It exists so a new
+// `~/.autofan` can be written against real `chip:label` strings
+// instead of guessing them from `sensors(1)` output.
+func listSensors() error {
+	var cfg listConfig
+
+	configFile := os.Getenv("HOME") + "/.autofan"
+
+	if content, err := ioutil.ReadFile(configFile); err == nil {
+		if err := yaml.Unmarshal(content, &cfg); err != nil {
+			return fmt.Errorf("reading yaml: %s", err)
+		}
+	}
+
+	source, err := newSensorSource(cfg.Source)
+
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.Disks) > 0 {
+		source, err = newDiskSource(source, cfg.Disks)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	defer source.Close()
+
+	values, err := source.Read()
+
+	if err != nil {
+		return fmt.Errorf("reading sensors: %s", err)
+	}
+
+	if len(values) == 0 {
+		return fmt.Errorf("no sensors detected")
+	}
+
+	var units map[string]string
+
+	if source, ok := source.(UnitSource); ok {
+		units = source.Units()
+	}
+
+	labelsByChip := make(map[string][]string)
+
+	for key, value := range values {
+		chip, label, found := strings.Cut(key, ":")
+
+		if !found {
+			chip, label = key, ""
+		}
+
+		entry := fmt.Sprintf("%s: %.1f", label, value)
+
+		if unit := units[key]; unit != "" {
+			entry += " " + unit
+		}
+
+		labelsByChip[chip] = append(labelsByChip[chip], entry)
+	}
+
+	chips := make([]string, 0, len(labelsByChip))
+
+	for chip := range labelsByChip {
+		chips = append(chips, chip)
+	}
+
+	sort.Strings(chips)
+
+	for _, chip := range chips {
+		fmt.Println(chip)
+
+		labels := labelsByChip[chip]
+		sort.Strings(labels)
+
+		for _, label := range labels {
+			fmt.Println("  " + label)
+		}
+	}
+
+	return nil
+}
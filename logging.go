@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// LogConfig configures autofan's structured logger. With no file set,
+// logging stays on stderr in text format, matching autofan's original
+// behavior.
+type LogConfig struct {
+	Level      string `yaml:"level"`
+	Format     string `yaml:"format"`
+	File       string `yaml:"file"`
+	MaxSizeMb  int    `yaml:"maxSizeMb"`
+	MaxBackups int    `yaml:"maxBackups"`
+	MaxAgeDays int    `yaml:"maxAgeDays"`
+}
+
+var (
+	log = logrus.New()
+
+	// rotatedOutput is the *lumberjack.Logger currently wired up as
+	// log's output, if any. It's tracked separately from log.Out
+	// because logrus locks that field internally on every write, and
+	// reading it back here without the same lock would race with
+	// in-flight log calls from zone goroutines.
+	rotatedOutput *lumberjack.Logger
+)
+
+// configureLogger applies cfg to the package-wide logger in place, so
+// every caller that already holds a reference keeps working after a
+// SIGHUP reload picks up new settings.
+func configureLogger(cfg LogConfig) error {
+	level := cfg.Level
+
+	if level == "" {
+		level = "info"
+	}
+
+	parsedLevel, err := logrus.ParseLevel(level)
+
+	if err != nil {
+		return fmt.Errorf("parsing log level: %s", err)
+	}
+
+	log.SetLevel(parsedLevel)
+
+	if cfg.Format == "json" {
+		log.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		log.SetFormatter(&logrus.TextFormatter{})
+	}
+
+	closeRotatedOutput()
+
+	if cfg.File == "" {
+		log.SetOutput(os.Stderr)
+		return nil
+	}
+
+	rotatedOutput = &lumberjack.Logger{
+		Filename:   cfg.File,
+		MaxSize:    intOr(cfg.MaxSizeMb, 10),
+		MaxBackups: intOr(cfg.MaxBackups, 5),
+		MaxAge:     intOr(cfg.MaxAgeDays, 14),
+	}
+
+	log.SetOutput(rotatedOutput)
+
+	return nil
+}
+
+// closeRotatedOutput closes the log file opened by a previous
+// configureLogger call, if any, so a SIGHUP reload doesn't leak its
+// descriptor.
+func closeRotatedOutput() {
+	if rotatedOutput == nil {
+		return
+	}
+
+	rotatedOutput.Close()
+	rotatedOutput = nil
+}
+
+func intOr(v, def int) int {
+	if v == 0 {
+		return def
+	}
+
+	return v
+}
@@ -5,35 +5,53 @@ import (
 	"io/ioutil"
 	"os"
 	"os/signal"
-	"regexp"
-	"strconv"
-	"strings"
-	"time"
+	"reflect"
+	"sync"
+	"syscall"
 
-	"github.com/md14454/gosensors"
 	"gopkg.in/yaml.v2"
 )
 
+// Autofan holds every zone autofan drives. A config file may either
+// declare an explicit `zones` list or, for backward compatibility with
+// the original single-fan format, the zone fields inline at the top
+// level -- in which case that single zone is used implicitly.
 type Autofan struct {
-	Mode       string   `yaml:"mode"`
-	Interval   string   `yaml:"interval"`
-	MinSpeed   int64    `yaml:"minSpeed"`
-	MaxSpeed   int64    `yaml:"maxSpeed"`
-	HighTemp   float64  `yaml:"highTemp"`
-	NormalTemp float64  `yaml:"normalTemp"`
-	Fan        string   `yaml:"fan"`
-	Output     string   `yaml:"output"`
-	Sensors    []string `yaml:"sensors"`
-	sensors    []*regexp.Regexp
-	interval   time.Duration
+	Zones   []*Zone       `yaml:"zones"`
+	Source  string        `yaml:"source"`
+	Disks   []string      `yaml:"disks"`
+	Metrics MetricsConfig `yaml:"metrics"`
+	Log     LogConfig     `yaml:"log"`
+	Zone    `yaml:",inline"`
+
+	zones []*Zone
 }
 
-type sensorsValues map[string]float64
-
 func main() {
-	var (
-		configFile = os.Getenv("HOME") + "/.autofan"
-		autofan    = &Autofan{
+	if len(os.Args) > 1 && os.Args[1] == "list" {
+		if err := listSensors(); err != nil {
+			fmt.Println("listing sensors:", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	configFile := os.Getenv("HOME") + "/.autofan"
+
+	autofan := newAutofan()
+
+	if err := autofan.configure(configFile); err != nil {
+		log.WithError(err).Fatal("configuring")
+	}
+
+	autofan.work(configFile)
+}
+
+func newAutofan() *Autofan {
+	return &Autofan{
+		Source: "libsensors",
+		Zone: Zone{
 			Mode:       "mean",
 			Interval:   "3s",
 			MinSpeed:   1500,
@@ -43,15 +61,8 @@ func main() {
 			Fan:        "applesmc-isa-0300:Master",
 			Output:     "/sys/devices/platform/applesmc.768/fan1_output",
 			Sensors:    []string{"coretemp-isa-0000:Core .*"},
-		}
-	)
-
-	if err := autofan.configure(configFile); err != nil {
-		fmt.Println("configuring:", err)
-		os.Exit(1)
+		},
 	}
-
-	autofan.work()
 }
 
 func (a *Autofan) configure(configFile string) error {
@@ -65,131 +76,128 @@ func (a *Autofan) configure(configFile string) error {
 		return fmt.Errorf("reading yaml: %s", err)
 	}
 
-	for _, sensor := range a.Sensors {
-		re, err := regexp.Compile(sensor)
+	if err := configureLogger(a.Log); err != nil {
+		return err
+	}
 
-		if err != nil {
-			return fmt.Errorf("build regex (%v): %v\n", sensor, err)
-		}
+	zones := a.Zones
 
-		a.sensors = append(a.sensors, re)
+	if len(zones) == 0 {
+		zones = []*Zone{&a.Zone}
 	}
 
-	interval, err := time.ParseDuration(a.Interval)
-
-	if err != nil {
-		return fmt.Errorf("parsing interval: %s", err)
+	for _, zone := range zones {
+		if err := zone.configure(); err != nil {
+			return err
+		}
 	}
 
-	a.interval = interval
+	a.zones = zones
 
 	return nil
 }
 
-func (a *Autofan) work() {
-	gosensors.Init()
-	defer gosensors.Cleanup()
+// work starts one goroutine per zone, all sharing the single
+// SensorSource, and blocks until interrupted. A SIGHUP re-reads
+// configFile and swaps in the new zones without restarting the
+// process; the sensor source itself is built once up front and is not
+// rebuilt on reload (see warnUnreloadable).
+func (a *Autofan) work(configFile string) {
+	source, err := newSensorSource(a.Source)
 
-	ticker := time.NewTicker(a.interval)
-	lastTemperature := 0.0
+	if err != nil {
+		log.WithError(err).Fatal("configuring sensor source")
+	}
 
-	go func() {
-		for range ticker.C {
-			temperatures, fanSpeed := a.fetchValues()
+	if len(a.Disks) > 0 {
+		source, err = newDiskSource(source, a.Disks)
 
-			if len(temperatures) == 0 {
-				fmt.Println("got no temperature values. check your configuration")
-				continue
-			}
+		if err != nil {
+			log.WithError(err).Fatal("configuring disk temperatures")
+		}
+	}
 
-			temperature, newFanSpeed, err := a.computeNewFanSpeed(temperatures)
+	source = newSyncSource(source)
 
-			if err != nil {
-				fmt.Println(err)
-				continue
-			}
+	defer source.Close()
 
-			if temperature == lastTemperature {
-				continue
-			}
+	if a.Metrics.Listen != "" {
+		serveMetrics(a.Metrics.Listen)
+	}
 
-			if err := ioutil.WriteFile(a.Output, []byte(strconv.Itoa(newFanSpeed)), 0644); err != nil {
-				fmt.Println("setting fan speed:", err)
-				continue
-			}
+	var zonesWg sync.WaitGroup
 
-			fmt.Printf("%v -- mean:%0.1f -- from %d RPM to %d RPM\n", temperatures, temperature, fanSpeed, newFanSpeed)
+	startZones := func(af *Autofan) chan struct{} {
+		stop := make(chan struct{})
 
-			lastTemperature = temperature
-		}
-	}()
+		for _, zone := range af.zones {
+			zonesWg.Add(1)
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt)
+			go func(z *Zone) {
+				defer zonesWg.Done()
+				z.run(stop, source)
+			}(zone)
+		}
 
-	<-sig
-	fmt.Println("signal received. exiting...")
-}
+		return stop
+	}
 
-func (a *Autofan) fetchValues() (sensorsValues, int) {
-	temperatures := make(sensorsValues)
-	fanSpeed := 0
+	stop := startZones(a)
 
-	for _, chip := range gosensors.GetDetectedChips() {
-		for _, feature := range chip.GetFeatures() {
-			sensorName := chip.String() + ":" + feature.GetLabel()
+	watchdogStop := make(chan struct{})
+	go watchdog(watchdogStop)
 
-			if strings.TrimSpace(sensorName) == a.Fan {
-				fanSpeed = int(feature.GetValue())
-				continue
-			}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGHUP)
 
-			if len(a.sensors) != 0 {
-				ok := false
+	for s := range sig {
+		if s != syscall.SIGHUP {
+			break
+		}
 
-				for _, re := range a.sensors {
-					if re.MatchString(sensorName) {
-						ok = true
-						break
-					}
-				}
+		log.Info("SIGHUP received. reloading configuration...")
 
-				if !ok {
-					continue
-				}
-			}
+		next := newAutofan()
 
-			temperatures[sensorName] = feature.GetValue()
+		if err := next.configure(configFile); err != nil {
+			log.WithError(err).Error("reloading configuration")
+			continue
 		}
-	}
 
-	return temperatures, fanSpeed
-}
+		warnUnreloadable(a, next)
 
-func (a *Autofan) computeNewFanSpeed(values sensorsValues) (float64, int, error) {
-	var sum, max, temp float64
+		close(stop)
+		stop = startZones(next)
+		a = next
+	}
 
-	for _, temperature := range values {
-		sum += temperature
+	log.Info("signal received. exiting...")
+	notifyStopping()
 
-		if temperature > max {
-			max = temperature
-		}
+	close(watchdogStop)
+	close(stop)
+	zonesWg.Wait()
+}
+
+// warnUnreloadable logs a warning for every setting SIGHUP can't
+// actually apply: the SensorSource (and, with it, the disk handles it
+// wraps and the metrics listener) is built once in work and never torn
+// down and rebuilt on reload, so a config editing `source`, `disks` or
+// `metrics.listen` keeps running against the old one until the process
+// is restarted.
+func warnUnreloadable(current, next *Autofan) {
+	if current.Source != next.Source {
+		log.WithField("from", current.Source).WithField("to", next.Source).
+			Warn("source changed on reload but the running sensor source was not rebuilt; restart autofan to apply it")
 	}
 
-	switch a.Mode {
-	case "mean":
-		temp = sum / float64(len(values))
-	case "max":
-		temp = max
-	default:
-		return 0, 0, fmt.Errorf("unrecognized mode '%s'. should be 'max' or 'mean'", a.Mode)
+	if !reflect.DeepEqual(current.Disks, next.Disks) {
+		log.WithField("from", current.Disks).WithField("to", next.Disks).
+			Warn("disks changed on reload but the running disk handles were not rebuilt; restart autofan to apply it")
 	}
 
-	return temp, int(
-		float64(a.MinSpeed) +
-			(float64(a.MaxSpeed-a.MinSpeed) /
-				(a.HighTemp - a.NormalTemp) *
-				(temp - a.NormalTemp)),
-	), nil
+	if current.Metrics.Listen != next.Metrics.Listen {
+		log.WithField("from", current.Metrics.Listen).WithField("to", next.Metrics.Listen).
+			Warn("metrics.listen changed on reload but the running metrics listener was not restarted; restart autofan to apply it")
+	}
 }
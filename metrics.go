@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsConfig enables the optional Prometheus `/metrics` and
+// `/healthz` HTTP endpoints.
+type MetricsConfig struct {
+	Listen string `yaml:"listen"`
+}
+
+var (
+	temperatureGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "autofan_temperature_celsius",
+		Help: "Last reading of each sensor feeding a zone.",
+	}, []string{"sensor"})
+
+	fanRPMGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "autofan_fan_rpm",
+		Help: "Last fan speed read back from each zone's fan.",
+	}, []string{"fan"})
+
+	fanSetpointGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "autofan_fan_setpoint_rpm",
+		Help: "Fan speed each zone last requested.",
+	}, []string{"fan"})
+
+	controlErrorsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "autofan_control_errors_total",
+		Help: "Errors encountered fetching sensors or computing a fan speed, per zone.",
+	}, []string{"zone"})
+
+	loopDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "autofan_loop_duration_seconds",
+		Help: "Time spent on one fetch/compute/apply iteration, per zone.",
+	}, []string{"zone"})
+
+	lastTickMu sync.Mutex
+	lastTickAt time.Time
+
+	// tickSuccesses counts successful zone ticks across the whole
+	// process; the systemd watchdog pings only while it keeps moving.
+	tickSuccesses uint64
+)
+
+func init() {
+	prometheus.MustRegister(
+		temperatureGauge,
+		fanRPMGauge,
+		fanSetpointGauge,
+		controlErrorsCounter,
+		loopDurationHistogram,
+	)
+}
+
+// recordTick feeds one zone iteration's outcome into the Prometheus
+// metrics. It is always safe to call, even when no metrics listener is
+// configured, so zones don't need to know whether metrics are enabled.
+func recordTick(zone *Zone, temperatures sensorsValues, fanSpeed, setpoint int, err error, duration time.Duration) {
+	loopDurationHistogram.WithLabelValues(zone.Name).Observe(duration.Seconds())
+
+	if err != nil {
+		controlErrorsCounter.WithLabelValues(zone.Name).Inc()
+		return
+	}
+
+	for sensor, temperature := range temperatures {
+		temperatureGauge.WithLabelValues(sensor).Set(temperature)
+	}
+
+	fanRPMGauge.WithLabelValues(zone.Fan).Set(float64(fanSpeed))
+	fanSetpointGauge.WithLabelValues(zone.Fan).Set(float64(setpoint))
+
+	lastTickMu.Lock()
+	lastTickAt = time.Now()
+	lastTickMu.Unlock()
+
+	atomic.AddUint64(&tickSuccesses, 1)
+}
+
+// serveMetrics starts the /metrics and /healthz HTTP endpoints in the
+// background. A listen failure is reported but doesn't stop autofan.
+func serveMetrics(listen string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", healthzHandler)
+
+	go func() {
+		if err := http.ListenAndServe(listen, mux); err != nil {
+			log.WithError(err).Error("metrics server")
+		}
+	}()
+}
+
+// healthzHandler reports the age of the last successful tick across
+// every zone, so a monitor can tell the daemon apart from a stalled one.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	lastTickMu.Lock()
+	last := lastTickAt
+	lastTickMu.Unlock()
+
+	if last.IsZero() {
+		http.Error(w, "no successful tick yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	fmt.Fprintf(w, "last successful tick: %s ago\n", time.Since(last).Round(time.Millisecond))
+}
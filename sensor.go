@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// SensorSource abstracts where temperature and fan readings come from,
+// so autofan isn't hard-bound to libsensors. Every implementation
+// flattens its readings into the same `chip:label` namespace the
+// zones' regex matchers already expect.
+type SensorSource interface {
+	Read() (map[string]float64, error)
+	Close()
+}
+
+// UnitSource is implemented by SensorSources that can also report a
+// unit hint (°C, RPM, V, ...) for the keys returned by their last
+// Read(), keyed the same way. It's optional because the unit is only
+// knowable from a feature's raw name, which some backends discard in
+// favor of a human label -- `autofan list` type-asserts for it instead
+// of it being part of SensorSource itself.
+type UnitSource interface {
+	Units() map[string]string
+}
+
+var sensorFeatureTypeRe = regexp.MustCompile(`^([a-z]+)\d*`)
+
+// sensorUnit guesses a feature's unit from its raw feature name (e.g.
+// "temp1", "fan2", "in0"), the same prefix convention hwmon.go's
+// matching keys off of.
+func sensorUnit(rawKey string) string {
+	switch sensorFeatureTypeRe.FindString(rawKey) {
+	case "temp":
+		return "°C"
+	case "fan":
+		return "RPM"
+	case "in":
+		return "V"
+	case "power":
+		return "W"
+	case "curr":
+		return "A"
+	default:
+		return ""
+	}
+}
+
+// newSensorSource builds the SensorSource selected by a zone's
+// `source` config: libsensors (the default, via cgo), sensorsExec
+// (shells out to `sensors -j`) or hwmon (reads /sys/class/hwmon
+// directly).
+func newSensorSource(name string) (SensorSource, error) {
+	switch name {
+	case "", "libsensors":
+		return newLibsensorsSource(), nil
+	case "sensorsExec":
+		return newSensorsExecSource(), nil
+	case "hwmon":
+		return newHwmonSource(), nil
+	default:
+		return nil, fmt.Errorf("unrecognized sensor source '%s'. should be 'libsensors', 'sensorsExec' or 'hwmon'", name)
+	}
+}
+
+// syncSource serializes Read() calls against an inner SensorSource.
+// autofan shares a single SensorSource across every zone's own ticker
+// goroutine, and libsensors in particular is unlocked cgo state that
+// lm-sensors never meant for concurrent access -- so every source
+// handed to more than one zone needs to go through this first.
+type syncSource struct {
+	inner SensorSource
+	mu    sync.Mutex
+}
+
+func newSyncSource(inner SensorSource) *syncSource {
+	return &syncSource{inner: inner}
+}
+
+func (s *syncSource) Read() (map[string]float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.inner.Read()
+}
+
+func (s *syncSource) Close() {
+	s.inner.Close()
+}
+
+// Units delegates to the wrapped source when it's a UnitSource,
+// guarded by the same lock as Read so it can't race a concurrent one.
+func (s *syncSource) Units() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if units, ok := s.inner.(UnitSource); ok {
+		return units.Units()
+	}
+
+	return nil
+}
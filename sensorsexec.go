@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// sensorsExecSource shells out to the `sensors` binary instead of
+// linking libsensors, for systems where only the CLI tool is
+// installed.
+type sensorsExecSource struct {
+	// units is rebuilt on every Read, keyed the same as its returned
+	// values.
+	units map[string]string
+}
+
+func newSensorsExecSource() *sensorsExecSource {
+	return &sensorsExecSource{}
+}
+
+func (s *sensorsExecSource) Read() (map[string]float64, error) {
+	output, err := exec.Command("sensors", "-j").Output()
+
+	if err != nil {
+		return nil, fmt.Errorf("running 'sensors -j': %s", err)
+	}
+
+	var tree map[string]map[string]json.RawMessage
+
+	if err := json.Unmarshal(output, &tree); err != nil {
+		return nil, fmt.Errorf("decoding 'sensors -j' output: %s", err)
+	}
+
+	values := make(map[string]float64)
+	units := make(map[string]string)
+
+	for chip, features := range tree {
+		for feature, rawFeature := range features {
+			var readings map[string]float64
+
+			if err := json.Unmarshal(rawFeature, &readings); err != nil {
+				continue
+			}
+
+			for key, value := range readings {
+				if !strings.HasSuffix(key, "_input") {
+					continue
+				}
+
+				sensorName := chip + ":" + feature
+				values[sensorName] = value
+				units[sensorName] = sensorUnit(strings.TrimSuffix(key, "_input"))
+			}
+		}
+	}
+
+	s.units = units
+
+	return values, nil
+}
+
+func (s *sensorsExecSource) Close() {}
+
+// Units reports the unit hints gathered by the most recent Read.
+func (s *sensorsExecSource) Units() map[string]string {
+	return s.units
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coreos/go-systemd/daemon"
+)
+
+var readyOnce sync.Once
+
+// notifyReady tells systemd the unit is ready. It's only ever invoked
+// through notifyReadyOnce, so it fires once, not at startup.
+func notifyReady() {
+	if _, err := daemon.SdNotify(false, daemon.SdNotifyReady); err != nil {
+		log.WithError(err).Warn("sd_notify ready")
+	}
+}
+
+// notifyReadyOnce calls notifyReady the first time any zone's sensor
+// read succeeds, so systemd isn't told the unit is ready before it can
+// actually see a temperature.
+func notifyReadyOnce() {
+	readyOnce.Do(notifyReady)
+}
+
+// notifyStopping tells systemd the unit is shutting down.
+func notifyStopping() {
+	if _, err := daemon.SdNotify(false, daemon.SdNotifyStopping); err != nil {
+		log.WithError(err).Warn("sd_notify stopping")
+	}
+}
+
+// watchdog pings systemd's watchdog at half of WATCHDOG_USEC, but only
+// when at least one zone tick has succeeded since the last ping. A
+// stuck gosensors call or a failed fan write then stalls the pings and
+// systemd restarts the unit instead of leaving it silently hung.
+func watchdog(stop <-chan struct{}) {
+	interval, err := daemon.SdWatchdogEnabled(false)
+
+	if err != nil || interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	var lastSeen uint64
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			seen := atomic.LoadUint64(&tickSuccesses)
+
+			if seen == lastSeen {
+				continue
+			}
+
+			lastSeen = seen
+
+			if _, err := daemon.SdNotify(false, daemon.SdNotifyWatchdog); err != nil {
+				log.WithError(err).Warn("sd_notify watchdog")
+			}
+		}
+	}
+}
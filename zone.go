@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type sensorsValues map[string]float64
+
+// Zone represents a single fan/sensor group: the sensors that feed it,
+// the fan used to read back its current speed and the output file used
+// to drive it. A config with no explicit `zones` list is treated as a
+// single implicit zone embedded in the top-level document.
+type Zone struct {
+	Name       string       `yaml:"name"`
+	Mode       string       `yaml:"mode"`
+	Interval   string       `yaml:"interval"`
+	MinSpeed   int64        `yaml:"minSpeed"`
+	MaxSpeed   int64        `yaml:"maxSpeed"`
+	HighTemp   float64      `yaml:"highTemp"`
+	NormalTemp float64      `yaml:"normalTemp"`
+	Curve      []CurvePoint `yaml:"curve"`
+	Hysteresis float64      `yaml:"hysteresis"`
+	UpStep     int64        `yaml:"upStep"`
+	DownStep   int64        `yaml:"downStep"`
+	Fan        string       `yaml:"fan"`
+	Output     string       `yaml:"output"`
+	Sensors    []string     `yaml:"sensors"`
+	sensors    []*regexp.Regexp
+	interval   time.Duration
+	lastTemp   *float64
+	lastRPM    int64
+}
+
+func (z *Zone) configure() error {
+	if z.Name == "" {
+		z.Name = z.Fan
+	}
+
+	for _, sensor := range z.Sensors {
+		re, err := regexp.Compile(sensor)
+
+		if err != nil {
+			return fmt.Errorf("build regex (%v): %v", sensor, err)
+		}
+
+		z.sensors = append(z.sensors, re)
+	}
+
+	interval, err := time.ParseDuration(z.Interval)
+
+	if err != nil {
+		return fmt.Errorf("parsing interval: %s", err)
+	}
+
+	z.interval = interval
+
+	if len(z.Curve) == 0 {
+		z.Curve = []CurvePoint{
+			{Temp: z.NormalTemp, RPM: z.MinSpeed},
+			{Temp: z.HighTemp, RPM: z.MaxSpeed},
+		}
+	}
+
+	sort.Slice(z.Curve, func(i, j int) bool {
+		return z.Curve[i].Temp < z.Curve[j].Temp
+	})
+
+	return nil
+}
+
+// run drives the zone's ticker loop until stop is closed, reading
+// sensors through the shared source.
+func (z *Zone) run(stop chan struct{}, source SensorSource) {
+	ticker := time.NewTicker(z.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			start := time.Now()
+			temperatures, fanSpeed := z.fetchValues(source)
+
+			if len(temperatures) == 0 {
+				err := fmt.Errorf("got no temperature values. check your configuration")
+				log.WithField("zone", z.Name).Warn(err)
+				recordTick(z, temperatures, fanSpeed, 0, err, time.Since(start))
+				continue
+			}
+
+			temperature, newFanSpeed, changed, err := z.computeNewFanSpeed(temperatures)
+
+			if err != nil {
+				log.WithField("zone", z.Name).WithField("fan", z.Fan).Error(err)
+				recordTick(z, temperatures, fanSpeed, 0, err, time.Since(start))
+				continue
+			}
+
+			if !changed {
+				recordTick(z, temperatures, fanSpeed, newFanSpeed, nil, time.Since(start))
+				continue
+			}
+
+			if err := ioutil.WriteFile(z.Output, []byte(strconv.Itoa(newFanSpeed)), 0644); err != nil {
+				log.WithField("zone", z.Name).WithField("fan", z.Fan).WithError(err).Error("setting fan speed")
+				recordTick(z, temperatures, fanSpeed, newFanSpeed, err, time.Since(start))
+				continue
+			}
+
+			log.WithFields(logrus.Fields{
+				"zone":         z.Name,
+				"sensors":      temperatures,
+				"reduced_temp": temperature,
+				"mode":         z.Mode,
+				"from_rpm":     fanSpeed,
+				"to_rpm":       newFanSpeed,
+			}).Info("fan speed updated")
+			recordTick(z, temperatures, fanSpeed, newFanSpeed, nil, time.Since(start))
+		}
+	}
+}
+
+func (z *Zone) fetchValues(source SensorSource) (sensorsValues, int) {
+	readings, err := source.Read()
+
+	if err != nil {
+		log.WithField("zone", z.Name).WithError(err).Error("reading sensors")
+		return sensorsValues{}, 0
+	}
+
+	notifyReadyOnce()
+
+	temperatures := make(sensorsValues)
+	fanSpeed := 0
+
+	for sensorName, value := range readings {
+		if strings.TrimSpace(sensorName) == z.Fan {
+			fanSpeed = int(value)
+			continue
+		}
+
+		if len(z.sensors) != 0 {
+			ok := false
+
+			for _, re := range z.sensors {
+				if re.MatchString(sensorName) {
+					ok = true
+					break
+				}
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		temperatures[sensorName] = value
+	}
+
+	return temperatures, fanSpeed
+}
+
+// computeNewFanSpeed reduces values to a single temperature, maps it
+// through the zone's curve and applies hysteresis/step limiting. It
+// reports whether the new speed actually differs from what was last
+// written so callers can skip redundant writes.
+func (z *Zone) computeNewFanSpeed(values sensorsValues) (float64, int, bool, error) {
+	temp, err := reduce(values, z.Mode)
+
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	targetRPM := int64(interpolateCurve(z.Curve, temp))
+
+	if z.lastTemp == nil {
+		z.lastTemp = &temp
+		z.lastRPM = targetRPM
+
+		return temp, int(targetRPM), true, nil
+	}
+
+	if math.Abs(temp-*z.lastTemp) < z.Hysteresis {
+		return temp, int(z.lastRPM), false, nil
+	}
+
+	newRPM := applyStep(z.lastRPM, targetRPM, z.UpStep, z.DownStep)
+	changed := newRPM != z.lastRPM
+
+	z.lastTemp = &temp
+	z.lastRPM = newRPM
+
+	return temp, int(newRPM), changed, nil
+}